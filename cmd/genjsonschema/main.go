@@ -0,0 +1,72 @@
+// Command genjsonschema infers a JSON Schema from a JSON or YAML sample and
+// prints it, optionally also emitting matching Go struct definitions.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/holgerjh/genjsonschema"
+	"github.com/holgerjh/genjsonschema/gengo"
+)
+
+func main() {
+	in := flag.String("in", "", "input sample file (json or yaml); defaults to stdin")
+	out := flag.String("out", "", "output schema file; defaults to stdout")
+	goOut := flag.String("go-out", "", "if set, also write generated Go struct definitions to this file")
+	goPackage := flag.String("go-package", "schema", "package name used in the -go-out output")
+	extractDefs := flag.Bool("extract-defs", false, "extract repeated object shapes into $defs; implied by -go-out, where each def becomes one exported type")
+	flag.Parse()
+
+	if err := run(*in, *out, *goOut, *goPackage, *extractDefs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, goOut, goPackage string, extractDefs bool) error {
+	data, err := readInput(in)
+	if err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+
+	config := genjsonschema.NewDefaultSchemaConfig()
+	config.ExtractDefinitions = extractDefs || goOut != ""
+	schemaBytes, view, err := genjsonschema.GenerateFromYAMLWithView(data, config)
+	if err != nil {
+		return fmt.Errorf("generating schema: %w", err)
+	}
+	if err := writeOutput(out, append(schemaBytes, '\n')); err != nil {
+		return fmt.Errorf("writing schema: %w", err)
+	}
+
+	if goOut == "" {
+		return nil
+	}
+
+	goSrc, err := gengo.Emit(view, gengo.Options{Package: goPackage})
+	if err != nil {
+		return fmt.Errorf("generating go types: %w", err)
+	}
+	if err := os.WriteFile(goOut, goSrc, 0o644); err != nil {
+		return fmt.Errorf("writing go types: %w", err)
+	}
+	return nil
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,421 @@
+// Package gengo generates Go type definitions from a genjsonschema.SchemaView:
+// one exported type per $defs entry (a struct for an object shape, otherwise
+// its map, scalar, or slice type), plus a root struct when the root describes
+// an object and isn't itself a $ref, with capitalized field names, JSON tags
+// preserving the original key, and slices/pointers for arrays and nullable
+// scalars.
+package gengo
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/holgerjh/genjsonschema"
+)
+
+// Options controls how Emit renders Go source.
+type Options struct {
+	// Package is the package name of the generated file. Defaults to "schema".
+	Package string
+
+	// RootTypeName names the struct generated for the schema's top-level
+	// object, when it isn't itself a $ref into Defs. Defaults to "Root".
+	RootTypeName string
+
+	// Initialisms overrides the set of lower-cased JSON key words that are
+	// rendered fully upper-cased (e.g. "id" -> "ID") instead of just having
+	// their first letter capitalized. If nil, DefaultInitialisms is used.
+	Initialisms map[string]bool
+}
+
+// DefaultInitialisms is the set of JSON key words recognized as initialisms
+// when Options.Initialisms is nil.
+var DefaultInitialisms = map[string]bool{
+	"id": true, "url": true, "uri": true, "api": true, "uuid": true,
+	"ip": true, "html": true, "http": true, "https": true, "json": true,
+	"xml": true,
+}
+
+// emitter accumulates the generated declarations for one Emit call.
+type emitter struct {
+	opts          Options
+	initialisms   map[string]bool
+	decls         []string          // one Go declaration (type or func) per entry, in emission order
+	helpers       map[string]bool   // names of anyOf-unmarshal helpers already emitted, to dedupe
+	usedTypeNames map[string]bool   // top-level type names already assigned, to dedupe
+	defTypeNames  map[string]string // $defs key -> the (possibly disambiguated) Go type name it was assigned
+	usesJSON      bool
+	usesFmt       bool
+}
+
+// Emit generates Go source declaring one named type per s.Defs entry (plus a
+// root struct, if the root describes an object and isn't itself a $ref),
+// following opts.
+func Emit(s *genjsonschema.SchemaView, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "schema"
+	}
+	if opts.RootTypeName == "" {
+		opts.RootTypeName = "Root"
+	}
+	initialisms := opts.Initialisms
+	if initialisms == nil {
+		initialisms = DefaultInitialisms
+	}
+	e := &emitter{
+		opts:          opts,
+		initialisms:   initialisms,
+		helpers:       map[string]bool{},
+		usedTypeNames: map[string]bool{},
+		defTypeNames:  map[string]string{},
+	}
+
+	defNames := make([]string, 0, len(s.Defs))
+	for name := range s.Defs {
+		defNames = append(defNames, name)
+	}
+	sort.Strings(defNames)
+	for _, name := range defNames {
+		typeName := uniqueName(e.usedTypeNames, exportedName(name, initialisms))
+		e.defTypeNames[name] = typeName
+		if err := e.emitNamedType(typeName, s.Defs[name]); err != nil {
+			return nil, fmt.Errorf("emitting def %q: %w", name, err)
+		}
+	}
+
+	if s.Ref == "" && s.Root != nil && isObjectLike(s.Root) {
+		rootName := uniqueName(e.usedTypeNames, opts.RootTypeName)
+		if err := e.emitNamedType(rootName, s.Root); err != nil {
+			return nil, fmt.Errorf("emitting root type: %w", err)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by gengo. DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	if e.usesJSON || e.usesFmt {
+		b.WriteString("import (\n")
+		if e.usesFmt {
+			b.WriteString("\"fmt\"\n")
+		}
+		if e.usesJSON {
+			b.WriteString("\"encoding/json\"\n")
+		}
+		b.WriteString(")\n\n")
+	}
+	for _, d := range e.decls {
+		b.WriteString(d)
+		b.WriteString("\n\n")
+	}
+
+	return format.Source([]byte(b.String()))
+}
+
+// isObjectLike reports whether p should be rendered as a named Go type
+// rather than skipped (a root that carries no object shape, e.g. a bare
+// scalar schema, has nothing for Emit to declare).
+func isObjectLike(p *genjsonschema.PropertyView) bool {
+	if contains(p.Type, "object") {
+		return true
+	}
+	return p.Properties != nil || p.AdditionalPropertiesSchema != nil
+}
+
+// emitNamedType renders p as a top-level declaration titled name: a struct
+// for an object with known properties, a named map type for a
+// homogeneous-value object (a Go map, from the reflection-based path), or the
+// underlying scalar/array type for a non-object def (e.g. an enum collapsed
+// into $defs by chunk0-2 extraction).
+func (e *emitter) emitNamedType(name string, p *genjsonschema.PropertyView) error {
+	if p.Properties == nil && p.AdditionalPropertiesSchema != nil {
+		valueType, err := e.goType(name, "Value", p.AdditionalPropertiesSchema)
+		if err != nil {
+			return err
+		}
+		e.decls = append(e.decls, fmt.Sprintf("type %s map[string]%s", name, valueType))
+		return nil
+	}
+
+	if !isObjectLike(p) {
+		underlying, err := e.goType(name, "", p)
+		if err != nil {
+			return err
+		}
+		e.decls = append(e.decls, fmt.Sprintf("type %s %s", name, underlying))
+		return nil
+	}
+
+	body, err := e.structBody(name, p)
+	if err != nil {
+		return err
+	}
+	e.decls = append(e.decls, fmt.Sprintf("type %s %s", name, body))
+	return nil
+}
+
+// structBody renders p's properties as a "struct { ... }" literal. context
+// names the enclosing type, used to derive names for anonymous nested types
+// and anyOf-unmarshal helper functions.
+func (e *emitter) structBody(context string, p *genjsonschema.PropertyView) (string, error) {
+	keys := make([]string, 0, len(p.Properties))
+	for k := range p.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	required := make(map[string]bool, len(p.Required))
+	for _, r := range p.Required {
+		required[r] = true
+	}
+
+	usedFieldNames := map[string]bool{}
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, k := range keys {
+		fieldName := uniqueName(usedFieldNames, exportedName(k, e.initialisms))
+		goType, err := e.goType(context, fieldName, p.Properties[k])
+		if err != nil {
+			return "", fmt.Errorf("field %q: %w", k, err)
+		}
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `json:\"%s\"`\n", fieldName, goType, tag)
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+// goType returns the Go type string for p. owner and field identify the
+// enclosing type and the field being rendered, used to name any anonymous
+// nested struct or anyOf-unmarshal helper that p requires.
+func (e *emitter) goType(owner, field string, p *genjsonschema.PropertyView) (string, error) {
+	if p.Ref != "" {
+		defName := refDefName(p.Ref)
+		typeName, ok := e.defTypeNames[defName]
+		if !ok {
+			return "", fmt.Errorf("$ref %q does not match any $defs entry", p.Ref)
+		}
+		return typeName, nil
+	}
+
+	nullable, base := splitNullable(p.Type)
+
+	switch {
+	case contains(base, "object") || (len(base) == 0 && (p.Properties != nil || p.AdditionalPropertiesSchema != nil)):
+		if p.Properties == nil && p.AdditionalPropertiesSchema != nil {
+			valueType, err := e.goType(owner, field+"Value", p.AdditionalPropertiesSchema)
+			if err != nil {
+				return "", err
+			}
+			return ptrIf(nullable, "map[string]"+valueType), nil
+		}
+		body, err := e.structBody(owner+field, p)
+		if err != nil {
+			return "", err
+		}
+		return ptrIf(nullable, body), nil
+	case contains(base, "array"):
+		t, err := e.arrayType(owner, field, p)
+		if err != nil {
+			return "", err
+		}
+		// A null array and an absent/empty one both marshal as a nil slice,
+		// so arrays are never pointer-wrapped even when Type includes null.
+		return t, nil
+	case len(base) == 1:
+		return ptrIf(nullable, scalarGoType(base[0])), nil
+	default:
+		// Either no type constraint at all, or a disjoint set of scalar
+		// types (e.g. ["boolean", "string"]) with no single Go
+		// representation.
+		return "interface{}", nil
+	}
+}
+
+// arrayType returns the Go type string for an array-typed property.
+func (e *emitter) arrayType(owner, field string, p *genjsonschema.PropertyView) (string, error) {
+	if len(p.ItemAlternatives) == 1 {
+		// A single alternative usually means every element shares one shape
+		// (e.g. a homogeneous list deduped down from several samples);
+		// render it as []T like p.Items instead of falling back to the
+		// anyOf helper. The exception is a disjoint scalar union (e.g.
+		// int|string, widened into one alternative with no single Go
+		// representation by goType) -- that one still needs the
+		// RawMessage+helper treatment below.
+		elem, err := e.goType(owner, field+"Item", p.ItemAlternatives[0])
+		if err != nil {
+			return "", err
+		}
+		if elem != "interface{}" {
+			return "[]" + elem, nil
+		}
+	}
+
+	switch {
+	case len(p.ItemAlternatives) > 0:
+		if err := e.emitAnyOfHelper(owner, field, p.ItemAlternatives); err != nil {
+			return "", err
+		}
+		e.usesJSON = true
+		return "[]json.RawMessage", nil
+	case len(p.PrefixItems) > 0:
+		// Go has no tuple type; prefixItems positions aren't individually
+		// represented.
+		return "[]interface{}", nil
+	case p.Items != nil:
+		elem, err := e.goType(owner, field+"Item", p.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	default:
+		return "[]interface{}", nil
+	}
+}
+
+// emitAnyOfHelper emits a package-level function that tries to unmarshal a
+// json.RawMessage as each of alts in turn, for callers of a
+// []json.RawMessage field produced by a heterogeneous anyOf items keyword.
+func (e *emitter) emitAnyOfHelper(owner, field string, alts []*genjsonschema.PropertyView) error {
+	helperName := "Unmarshal" + owner + field
+	if e.helpers[helperName] {
+		return nil
+	}
+	e.helpers[helperName] = true
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s tries to unmarshal data as each of the schema's anyOf\n", helperName)
+	b.WriteString("// alternatives in turn, returning the first one that succeeds.\n")
+	fmt.Fprintf(&b, "func %s(data json.RawMessage) (interface{}, error) {\n", helperName)
+	for i, alt := range alts {
+		t, err := e.goType(owner, fmt.Sprintf("%sAlt%d", field, i), alt)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "var v%d %s\n", i, t)
+		fmt.Fprintf(&b, "if err := json.Unmarshal(data, &v%d); err == nil {\n", i)
+		fmt.Fprintf(&b, "return v%d, nil\n}\n", i)
+	}
+	b.WriteString("return nil, fmt.Errorf(\"value matches none of the schema's anyOf alternatives\")\n}")
+	e.usesJSON = true
+	e.usesFmt = true
+	e.decls = append(e.decls, b.String())
+	return nil
+}
+
+// uniqueName returns base, or base suffixed with the lowest integer >= 2
+// that isn't already in used, and records whichever name it returns as used.
+// It disambiguates names that collide after exportedName normalizes two
+// distinct JSON keys (e.g. "user_id" and "userId") to the same identifier.
+func uniqueName(used map[string]bool, base string) string {
+	name := base
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	used[name] = true
+	return name
+}
+
+// refDefName extracts the $defs key from a "#/$defs/Name" ref.
+func refDefName(ref string) string {
+	return strings.TrimPrefix(ref, "#/$defs/")
+}
+
+// exportedName converts a JSON key into an exported Go identifier,
+// capitalizing each word and upper-casing words found in initialisms.
+func exportedName(key string, initialisms map[string]bool) string {
+	var b strings.Builder
+	for _, w := range splitWords(key) {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[:1])))
+		b.WriteString(string(r[1:]))
+	}
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+// splitWords splits a JSON key into words on '_', '-', '.', ' ', and
+// camelCase boundaries.
+func splitWords(key string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(key)
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return words
+}
+
+// scalarGoType maps a jsonschema scalar type keyword to its Go equivalent.
+func scalarGoType(t string) string {
+	switch t {
+	case "boolean":
+		return "bool"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "string":
+		return "string"
+	default:
+		return "interface{}"
+	}
+}
+
+// splitNullable reports whether types includes "null", and returns the
+// remaining non-null types.
+func splitNullable(types []string) (nullable bool, rest []string) {
+	for _, t := range types {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		rest = append(rest, t)
+	}
+	return nullable, rest
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func ptrIf(nullable bool, t string) string {
+	if nullable {
+		return "*" + t
+	}
+	return t
+}
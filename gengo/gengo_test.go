@@ -0,0 +1,178 @@
+package gengo
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/holgerjh/genjsonschema"
+)
+
+func mustGenerateSchema(t *testing.T, sample string, config *genjsonschema.SchemaConfig) *genjsonschema.SchemaView {
+	t.Helper()
+	view, err := genjsonschema.GenerateSchema([]byte(sample), config)
+	if err != nil {
+		t.Fatalf("GenerateSchema: %v", err)
+	}
+	return view
+}
+
+// requireField checks that got declares a field named name of the given Go
+// type and json tag, tolerating whatever column padding gofmt inserted.
+func requireField(t *testing.T, got []byte, name, goType, tag string) {
+	t.Helper()
+	pattern := regexp.MustCompile(regexp.QuoteMeta(name) + `\s+` + regexp.QuoteMeta(goType) + "\\s+`json:\"" + regexp.QuoteMeta(tag) + "\"`")
+	if !pattern.Match(got) {
+		t.Errorf("generated source missing field %s %s `json:%q`, got:\n%s", name, goType, tag, got)
+	}
+}
+
+func TestEmitStructFieldsAndTags(t *testing.T) {
+	view := mustGenerateSchema(t, `{"user_id": 1, "name": "alice"}`,
+		&genjsonschema.SchemaConfig{RequireAllProperties: true})
+
+	got, err := Emit(view, Options{Package: "sample"})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if !strings.Contains(string(got), "package sample") {
+		t.Errorf("generated source missing package clause, got:\n%s", got)
+	}
+	requireField(t, got, "UserID", "int", "user_id")
+	requireField(t, got, "Name", "string", "name")
+}
+
+func TestEmitOmitsEmptyForOptionalFields(t *testing.T) {
+	view := mustGenerateSchema(t, `{"name": "alice"}`,
+		&genjsonschema.SchemaConfig{RequireAllProperties: false})
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if !strings.Contains(string(got), `json:"name,omitempty"`) {
+		t.Errorf("expected an omitempty tag for a non-required field, got:\n%s", got)
+	}
+}
+
+func TestEmitDefsBecomeNamedTypesWithRefFields(t *testing.T) {
+	view := mustGenerateSchema(t, `{"a": {"x": 1}, "b": {"x": 2}}`,
+		&genjsonschema.SchemaConfig{ExtractDefinitions: true, RequireAllProperties: true})
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	for _, want := range []string{"type A struct {", "A A `json:\"a\"`", "B A `json:\"b\"`"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generated source missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestEmitNullableScalarBecomesPointer(t *testing.T) {
+	view := mustGenerateSchema(t, `{"name": "alice"}`, nil)
+	view.Root.Properties["name"].Type = []string{"null", "string"}
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if !strings.Contains(string(got), "Name *string") {
+		t.Errorf("expected a pointer field for a nullable scalar, got:\n%s", got)
+	}
+}
+
+func TestEmitHeterogeneousArrayBecomesRawMessageWithHelper(t *testing.T) {
+	view := mustGenerateSchema(t, `{"vals": [1, "a"]}`, nil)
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	for _, want := range []string{
+		"Vals []json.RawMessage",
+		"func UnmarshalRootVals(data json.RawMessage) (interface{}, error) {",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generated source missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestEmitHomogeneousArrayBecomesTypedSlice(t *testing.T) {
+	view := mustGenerateSchema(t, `{"tags": ["x", "y"]}`, nil)
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	requireField(t, got, "Tags", "[]string", "tags")
+	if strings.Contains(string(got), "json.RawMessage") {
+		t.Errorf("homogeneous array should not fall back to json.RawMessage, got:\n%s", got)
+	}
+}
+
+func TestEmitEnumDefBecomesScalarType(t *testing.T) {
+	view := mustGenerateSchema(t, `{"a": "open", "b": "open"}`,
+		&genjsonschema.SchemaConfig{InferStringConstraints: true, ExtractDefinitions: true, RequireAllProperties: true})
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if !strings.Contains(string(got), "type A string") {
+		t.Errorf("expected an enum def to become its scalar Go type, got:\n%s", got)
+	}
+	requireField(t, got, "A", "A", "a")
+	requireField(t, got, "B", "A", "b")
+}
+
+func TestEmitDisambiguatesCollidingFieldNames(t *testing.T) {
+	view := mustGenerateSchema(t, `{"user_id": 1, "userId": 2}`,
+		&genjsonschema.SchemaConfig{RequireAllProperties: true})
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	requireField(t, got, "UserID", "int", "userId")
+	requireField(t, got, "UserID2", "int", "user_id")
+}
+
+func TestEmitDisambiguatesCollidingDefAndRootTypeNames(t *testing.T) {
+	view := mustGenerateSchema(t, `{"root": {"x": 1}, "zzz": {"x": 2}}`,
+		&genjsonschema.SchemaConfig{ExtractDefinitions: true, RequireAllProperties: true})
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if !strings.Contains(string(got), "type Root struct {") || !strings.Contains(string(got), "type Root2 struct {") {
+		t.Errorf("expected distinct Root and Root2 type declarations, got:\n%s", got)
+	}
+}
+
+func TestEmitInitialismsAreUpperCased(t *testing.T) {
+	view := mustGenerateSchema(t, `{"user_id": "abc", "api_key": "xyz"}`, nil)
+
+	got, err := Emit(view, Options{})
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	for _, want := range []string{"UserID string", "APIKey string"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generated source missing %q, got:\n%s", want, got)
+		}
+	}
+}
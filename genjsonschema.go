@@ -1,14 +1,18 @@
 /*
 Package genjsonschema implements a simple JSON Schema generator.
-It generates schemas in accordance with https://json-schema.org/draft-07/schema.
+By default it generates schemas in accordance with https://json-schema.org/draft-07/schema;
+set SchemaConfig.Draft to Draft202012 to target https://json-schema.org/draft/2020-12/schema instead.
 It supports json and a subset of YAML (notably, mappings may only have string keys).
 
-
-Lists will always be defined using the anyOf keyword and won't be limited on item numbers.
-A schema generated from [1, true] will thus accept a list with an undefined number of integers,
-booleans, and combinations thereof, but will reject other element types such as string.
+In draft-07 output, lists are always defined using the anyOf keyword and won't be limited on
+item numbers. A schema generated from [1, true] will thus accept a list with an undefined number
+of integers, booleans, and combinations thereof, but will reject other element types such as
+string. 2020-12 output instead follows SchemaConfig.ArrayMode: ArrayModeList (the default) merges
+every observed element shape into a single items schema, while ArrayModeTuple renders an array
+whose elements are all distinct shapes as prefixItems with no further items allowed.
 
 Example:
+
 	from := []byte("{'foo': 'bar'}")
 	schema, err := GenerateFromJSON(from, nil)
 	if err != nil {
@@ -21,20 +25,130 @@ Example:
 package genjsonschema
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 const jsonSchemaRef = "http://json-schema.org/draft-07/schema"
+const jsonSchema202012Ref = "https://json-schema.org/draft/2020-12/schema"
+
+// Draft selects which JSON Schema draft GenerateFrom* functions target. The
+// zero value behaves like Draft7.
+type Draft string
+
+const (
+	// Draft7 generates schemas in accordance with https://json-schema.org/draft-07/schema.
+	// It is the default.
+	Draft7 Draft = "draft-07"
+	// Draft202012 generates schemas in accordance with
+	// https://json-schema.org/draft/2020-12/schema, and lets SchemaConfig.ArrayMode
+	// render tuple-typed arrays with prefixItems instead of anyOf.
+	Draft202012 Draft = "2020-12"
+)
+
+// effectiveDraft returns config.Draft, defaulting to Draft7 for the zero
+// value or a nil config.
+func effectiveDraft(config *SchemaConfig) Draft {
+	if config != nil && config.Draft == Draft202012 {
+		return Draft202012
+	}
+	return Draft7
+}
+
+// schemaURI returns the $schema value to use for draft.
+func schemaURI(draft Draft) string {
+	if draft == Draft202012 {
+		return jsonSchema202012Ref
+	}
+	return jsonSchemaRef
+}
+
+// ArrayMode controls how property.addArray renders an array whose elements
+// have distinct shapes. It only takes effect when SchemaConfig.Draft is
+// Draft202012; Draft7 output always uses the legacy items.anyOf form. The
+// zero value behaves like ArrayModeList.
+type ArrayMode string
+
+const (
+	// ArrayModeList merges every observed element shape into a single items
+	// schema, matching how idiomatic 2020-12 schemas describe a homogeneous
+	// list. It is the default.
+	ArrayModeList ArrayMode = "list"
+	// ArrayModeTuple renders an array whose elements are all distinct shapes
+	// as prefixItems (one schema per index) with items: false, forbidding
+	// elements beyond those observed. Arrays whose elements aren't all
+	// distinct fall back to ArrayModeList, as do arrays merged from several
+	// samples or from several elements of an enclosing array.
+	ArrayModeTuple ArrayMode = "tuple"
+)
+
+// effectiveArrayMode returns config.ArrayMode, defaulting to ArrayModeList
+// for the zero value or a nil config.
+func effectiveArrayMode(config *SchemaConfig) ArrayMode {
+	if config != nil && config.ArrayMode == ArrayModeTuple {
+		return ArrayModeTuple
+	}
+	return ArrayModeList
+}
 
 // SchemaConfig holds configuration used when generating a schema
 type SchemaConfig struct {
 	ID                   string // $id field value of the schema, omitted if empty
 	AdditionalProperties bool   // Whether the schema allow objects to have previously unknown properties
 	RequireAllProperties bool   // Whether the schema requires taht all properties of an object are set
+
+	// ExtractDefinitions moves object shapes, and scalars carrying an inferred
+	// enum, that occur at two or more locations
+	// of the generated schema into a top-level $defs entry, replacing every
+	// occurrence with a $ref. It is off by default, which keeps today's fully
+	// inlined output.
+	ExtractDefinitions bool
+
+	// DefinitionNaming names a $defs entry given the JSON path of its first
+	// occurrence in the document (e.g. []string{"users", "0", "address"}). If
+	// nil, the path segments are joined with "_".
+	DefinitionNaming func(path []string) string
+
+	// InferStringConstraints enriches string properties with an "enum" (when
+	// few enough distinct values were observed) and a "format" (when every
+	// observed value matches the same detector from FormatDetectors).
+	InferStringConstraints bool
+
+	// MaxEnumValues caps how many distinct observed values still produce an
+	// "enum". If zero, it defaults to 20.
+	MaxEnumValues int
+
+	// FormatDetectors overrides the built-in format detectors (RFC3339
+	// date-time, email, uri, uuid, ipv4, ipv6) used by InferStringConstraints.
+	// If nil, the built-ins are used.
+	FormatDetectors []func(value string) (format string, ok bool)
+
+	// MinMaxNumeric records the observed minimum and maximum of integer and
+	// number properties as "minimum"/"maximum".
+	MinMaxNumeric bool
+
+	// Draft selects the JSON Schema draft targeted by the output. The zero
+	// value (Draft7) preserves today's draft-07 output.
+	Draft Draft
+
+	// ArrayMode controls how arrays whose elements have distinct shapes are
+	// rendered when Draft is Draft202012; it has no effect otherwise. The
+	// zero value (ArrayModeList) merges every element shape into a single
+	// items schema.
+	ArrayMode ArrayMode
 }
 
 // NewSchemaConfig returns a new SchemaConfig.
@@ -62,9 +176,12 @@ func GenerateFromJSON(json []byte, schemaConfig *SchemaConfig) ([]byte, error) {
 
 // GenerateFromYAML generates a JSON Schema from yaml.
 // It requires that all mapping keys are strings, i.e. the following is fine:
-//   foo: "bar"  # ok because "foo" is of type string
+//
+//	foo: "bar"  # ok because "foo" is of type string
+//
 // but the following is not fine:
-//   42: "bar"  # not ok because 42 is an integer
+//
+//	42: "bar"  # not ok because 42 is an integer
 //
 // If schemaConfig is nil, NewDefaultSchemaConfig will be used.
 func GenerateFromYAML(yaml []byte, schemaConfig *SchemaConfig) ([]byte, error) {
@@ -75,12 +192,349 @@ func GenerateFromYAML(yaml []byte, schemaConfig *SchemaConfig) ([]byte, error) {
 	return schema.Marshal()
 }
 
+// GenerateSchema generates a schema from yaml (or json, since YAML is a
+// superset of JSON) like GenerateFromYAML, but returns the inferred
+// structure as a *SchemaView instead of marshaled bytes. It is the entry
+// point for tools, such as gengo, that need to work with the schema's shape
+// directly instead of reparsing its JSON representation.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateSchema(yamlOrJSON []byte, schemaConfig *SchemaConfig) (*SchemaView, error) {
+	s, err := newSchemaFromYAML(yamlOrJSON, schemaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(), nil
+}
+
+// GenerateFromYAMLWithView is like GenerateFromYAML, but also returns the
+// inferred structure as a *SchemaView without re-running inference, for
+// callers that need both the marshaled schema and its shape.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateFromYAMLWithView(yamlOrJSON []byte, schemaConfig *SchemaConfig) ([]byte, *SchemaView, error) {
+	s, err := newSchemaFromYAML(yamlOrJSON, schemaConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := s.Marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, s.view(), nil
+}
+
+// GenerateFromJSONSamples generates a single JSON Schema that accepts every one of
+// the given json samples. It is useful to derive a schema from a corpus of real
+// documents instead of a single handcrafted example: a property is only marked
+// required if it was present in all samples, and properties that differ in shape
+// across samples are merged rather than kept as separate alternatives.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateFromJSONSamples(samples [][]byte, schemaConfig *SchemaConfig) ([]byte, error) {
+	return GenerateFromYAMLSamples(samples, schemaConfig) // YAML is a superset of JSON
+}
+
+// GenerateFromYAMLSamples is the multi-document counterpart of GenerateFromYAML.
+// See GenerateFromJSONSamples for details on how samples are merged.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateFromYAMLSamples(samples [][]byte, schemaConfig *SchemaConfig) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("at least one sample is required")
+	}
+	if schemaConfig == nil {
+		schemaConfig = NewDefaultSchemaConfig()
+	}
+
+	var merged *property
+	for _, sample := range samples {
+		var data interface{}
+		if err := yaml.Unmarshal(sample, &data); err != nil {
+			return nil, err
+		}
+		p, err := newProperty(data, schemaConfig)
+		if err != nil {
+			return nil, err
+		}
+		merged = merge(merged, p, schemaConfig)
+	}
+
+	s := &schema{
+		JsonSchemaRef: schemaURI(effectiveDraft(schemaConfig)),
+		ID:            schemaConfig.ID,
+		property:      *merged,
+	}
+	applyDefinitionExtraction(s, schemaConfig)
+	return s.Marshal()
+}
+
+// applyDefinitionExtraction populates s.Defs and rewrites s.property with $ref
+// entries when config.ExtractDefinitions is set. It is a no-op otherwise.
+// Entries already present in s.Defs (e.g. from recursive types found by
+// GenerateFromType) are preserved and merged with, rather than replaced by,
+// the newly extracted ones.
+func applyDefinitionExtraction(s *schema, config *SchemaConfig) {
+	if !config.ExtractDefinitions {
+		return
+	}
+	if s.Defs == nil {
+		s.Defs = map[string]*property{}
+	}
+	extractDefinitions(&s.property, s.Defs, config)
+}
+
+// GenerateFromValue generates a JSON Schema describing the type of v. It is
+// a convenience wrapper around GenerateFromType for callers that already
+// have a value rather than a reflect.Type.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateFromValue(v interface{}, schemaConfig *SchemaConfig) ([]byte, error) {
+	return GenerateFromType(reflect.TypeOf(v), schemaConfig)
+}
+
+// GenerateFromType generates a JSON Schema describing t, walking it with
+// reflect instead of inferring it from sample data. It shares marshaling,
+// $defs extraction, and config semantics with the sample-based functions, so
+// ID, AdditionalProperties, ExtractDefinitions, DefinitionNaming, and Draft
+// still apply. RequireAllProperties does not: a struct field's required-ness
+// comes only from its json tag and whether it is a pointer. Fields relying on
+// observed values (InferStringConstraints, MinMaxNumeric) have nothing to
+// infer from and are left unset.
+//
+// Go kinds map as follows: bool -> boolean; signed/unsigned integers ->
+// integer; floats -> number; string -> string; slices and arrays -> array,
+// with items describing the single element type; maps with string keys ->
+// object, with additionalProperties describing the value type; pointers ->
+// the pointed-to type, made optional wherever they appear as a struct field
+// (not required, and "null" added to its type unless it is a $ref to a
+// recursive type); and structs
+// -> object, using the "json" tag of each field for its property name and
+// required-ness, flattening embedded structs, and honoring `json:"-"`.
+//
+// A struct type that is recursive, directly or through a chain of other
+// types, is extracted into $defs the first time the cycle closes, regardless
+// of SchemaConfig.ExtractDefinitions, since inlining it would recurse
+// forever.
+//
+// If schemaConfig is nil, a NewDefaultSchemaConfig will be used.
+func GenerateFromType(t reflect.Type, schemaConfig *SchemaConfig) ([]byte, error) {
+	if schemaConfig == nil {
+		schemaConfig = NewDefaultSchemaConfig()
+	}
+
+	defs := map[string]*property{}
+	p, err := propertyFromType(t, schemaConfig, map[reflect.Type]string{}, map[reflect.Type]bool{}, defs)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		JsonSchemaRef: schemaURI(effectiveDraft(schemaConfig)),
+		ID:            schemaConfig.ID,
+	}
+	// p.Ref is set when t is itself recursive (e.g. GenerateFromType(Node{})
+	// where Node refers to itself): SchemaEntryRef, not property.Ref, is what
+	// actually reaches the marshaled output, since schema embeds property and
+	// both map to the "$ref" key.
+	if p.Ref != "" {
+		s.SchemaEntryRef = p.Ref
+	} else {
+		s.property = *p
+	}
+	if len(defs) > 0 {
+		s.Defs = defs
+	}
+	applyDefinitionExtraction(s, schemaConfig)
+	return s.Marshal()
+}
+
+// propertyFromType builds a property tree for t. inProgress maps a struct
+// type to the $defs name reserved for it while its property tree is still
+// being built; encountering that type again means t is recursive, and the
+// cycle is closed with a $ref into defs instead of recursing forever.
+// recursed records which of the types in inProgress actually had such a
+// cycle closed against it, so propertyFromStruct knows whether it needs to
+// move itself into defs once its own tree is complete.
+func propertyFromType(t reflect.Type, config *SchemaConfig, inProgress map[reflect.Type]string, recursed map[reflect.Type]bool, defs map[string]*property) (*property, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if name, ok := inProgress[t]; ok {
+		recursed[t] = true
+		return &property{Ref: "#/$defs/" + name}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &property{Type: typeOf(typeBoolean)}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &property{Type: typeOf(typeInteger)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &property{Type: typeOf(typeNumber)}, nil
+	case reflect.String:
+		return &property{Type: typeOf(typeString)}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := propertyFromType(t.Elem(), config, inProgress, recursed, defs)
+		if err != nil {
+			return nil, err
+		}
+		return &property{Type: typeOf(typeArray), Items: &items{Item: elem}}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s, only string keys are supported", t.Key())
+		}
+		value, err := propertyFromType(t.Elem(), config, inProgress, recursed, defs)
+		if err != nil {
+			return nil, err
+		}
+		return &property{
+			Type:                 typeOf(typeObject),
+			AdditionalProperties: &additionalProperties{Schema: value},
+		}, nil
+	case reflect.Struct:
+		return propertyFromStruct(t, config, inProgress, recursed, defs)
+	default:
+		return nil, fmt.Errorf("unsupported type %s of kind %s", t, t.Kind())
+	}
+}
+
+// propertyFromStruct builds an object property from a struct type, using the
+// "json" tag of each (possibly embedded) field for its property name and
+// required-ness. If building the struct's own fields closed a cycle back to
+// t, the finished property is moved into defs and a $ref is returned in its
+// place instead, so that every occurrence of the recursive type shares one
+// $defs entry.
+func propertyFromStruct(t reflect.Type, config *SchemaConfig, inProgress map[reflect.Type]string, recursed map[reflect.Type]bool, defs map[string]*property) (*property, error) {
+	name := uniqueDefinitionName(t, defs)
+	inProgress[t] = name
+	defer delete(inProgress, t)
+
+	p := &property{Type: typeOf(typeObject)}
+	if !config.AdditionalProperties {
+		p.AdditionalProperties = additionalPropertiesBool(false)
+	}
+
+	fields, err := flattenStructFields(t)
+	if err != nil {
+		return nil, err
+	}
+	props := properties{}
+	for _, f := range fields {
+		fieldName, omitempty, ok := jsonFieldName(f)
+		if !ok {
+			continue
+		}
+		child, err := propertyFromType(f.Type, config, inProgress, recursed, defs)
+		if err != nil {
+			return nil, err
+		}
+		// A pointer field is optional: absence is allowed by simply not
+		// requiring it, and an explicit null is allowed by widening its type.
+		// A $ref can't be widened like this (every other field must be left
+		// unset alongside $ref), so a pointer to a recursive type is only
+		// optional, not nullable.
+		if f.Type.Kind() == reflect.Ptr && child.Ref == "" {
+			child.Type = append(child.Type, typeNull)
+			sort.Slice(child.Type, func(i, j int) bool { return child.Type[i] < child.Type[j] })
+		}
+		props[fieldName] = child
+		if !omitempty && f.Type.Kind() != reflect.Ptr {
+			p.Required = append(p.Required, fieldName)
+		}
+	}
+	if len(props) > 0 {
+		p.Properties = &props
+	}
+	sort.Strings(p.Required)
+
+	if recursed[t] {
+		delete(recursed, t)
+		defs[name] = p
+		return &property{Ref: "#/$defs/" + name}, nil
+	}
+	return p, nil
+}
+
+// uniqueDefinitionName returns a $defs name for t that is not already taken
+// in defs, based on t's own type name (falling back to "type" for anonymous
+// struct types) and disambiguated with a numeric suffix on collision.
+func uniqueDefinitionName(t reflect.Type, defs map[string]*property) string {
+	base := t.Name()
+	if base == "" {
+		base = "type"
+	}
+	for i := 0; ; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%d", base, i)
+		}
+		if _, taken := defs[candidate]; !taken {
+			return candidate
+		}
+	}
+}
+
+// flattenStructFields returns every field of t that should become a schema
+// property, recursing into anonymous (embedded) struct fields so that their
+// fields are promoted to t's own level, matching how encoding/json treats
+// them.
+func flattenStructFields(t reflect.Type) ([]reflect.StructField, error) {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			embedded := f.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+				nested, err := flattenStructFields(embedded)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, nested...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // unexported, not visible to encoding/json
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// jsonFieldName returns the property name and required-ness of a struct
+// field according to its "json" tag, and whether it should be included in
+// the schema at all (false for `json:"-"`).
+func jsonFieldName(f reflect.StructField) (name string, omitempty bool, ok bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
+
 // Schema represents a json schema. Exported fields correspond to attributes of the schema
 // whereas unexported fields are internally used when generating the schema.
 type schema struct {
-	JsonSchemaRef  string `json:"$schema"`
-	SchemaEntryRef string `json:"$ref,omitempty"`
-	ID             string `json:"$id,omitempty"`
+	JsonSchemaRef  string               `json:"$schema"`
+	SchemaEntryRef string               `json:"$ref,omitempty"`
+	ID             string               `json:"$id,omitempty"`
+	Defs           map[string]*property `json:"$defs,omitempty"`
 	property       `json:",omitempty"`
 }
 
@@ -101,7 +555,7 @@ func newSchemaFromYAML(b []byte, schemaConfig *SchemaConfig) (*schema, error) {
 
 func generateSchema(from interface{}, config *SchemaConfig) (*schema, error) {
 	s := &schema{
-		JsonSchemaRef: jsonSchemaRef,
+		JsonSchemaRef: schemaURI(effectiveDraft(config)),
 		ID:            config.ID,
 	}
 	p, err := newProperty(from, config)
@@ -109,6 +563,7 @@ func generateSchema(from interface{}, config *SchemaConfig) (*schema, error) {
 		return nil, err
 	}
 	s.property = *p
+	applyDefinitionExtraction(s, config)
 	return s, nil
 }
 
@@ -117,31 +572,214 @@ func (s *schema) Marshal() ([]byte, error) {
 	return json.Marshal(s)
 }
 
+// SchemaView is an exported, read-only view of a generated schema's
+// structure. It holds the structure of Marshal's JSON output, with $ref
+// entries preserved instead of needing to be parsed back out of the
+// marshaled form. The $schema URI and an "items": false keyword are not
+// represented.
+type SchemaView struct {
+	ID   string
+	Ref  string // set instead of Root when the top level is itself a $ref, e.g. for a recursive Go type
+	Root *PropertyView
+	Defs map[string]*PropertyView
+}
+
+// PropertyView is an exported, read-only view of a property.
+type PropertyView struct {
+	Type       []string
+	Properties map[string]*PropertyView
+	Required   []string
+
+	// AdditionalPropertiesAllowed is set when additionalProperties is a plain
+	// bool (the sample-based generation path, and structs in the
+	// reflection-based path), and nil otherwise.
+	AdditionalPropertiesAllowed *bool
+	// AdditionalPropertiesSchema is set when additionalProperties constrains
+	// the type of unknown properties (the reflection-based path, for a Go
+	// map's value type), and nil otherwise.
+	AdditionalPropertiesSchema *PropertyView
+
+	// Items is set when every array element must match the same schema.
+	Items *PropertyView
+	// ItemAlternatives is set when array elements may match any one of
+	// several distinct schemas (an anyOf items keyword).
+	ItemAlternatives []*PropertyView
+	// PrefixItems holds the per-position schemas of a tuple-typed array.
+	PrefixItems []*PropertyView
+
+	Ref     string
+	Enum    []interface{}
+	Format  string
+	Minimum *float64
+	Maximum *float64
+}
+
+// view converts s to its exported representation.
+func (s *schema) view() *SchemaView {
+	v := &SchemaView{ID: s.ID, Ref: s.SchemaEntryRef}
+	if v.Ref == "" {
+		v.Root = s.property.view()
+	}
+	if len(s.Defs) > 0 {
+		v.Defs = make(map[string]*PropertyView, len(s.Defs))
+		for name, p := range s.Defs {
+			v.Defs[name] = p.view()
+		}
+	}
+	return v
+}
+
+// view converts p to its exported representation.
+func (p *property) view() *PropertyView {
+	if p == nil {
+		return nil
+	}
+	v := &PropertyView{
+		Required: p.Required,
+		Ref:      p.Ref,
+		Enum:     p.Enum,
+		Format:   p.Format,
+		Minimum:  p.Minimum,
+		Maximum:  p.Maximum,
+	}
+	for _, t := range p.Type {
+		v.Type = append(v.Type, string(t))
+	}
+	if p.Properties != nil {
+		v.Properties = make(map[string]*PropertyView, len(*p.Properties))
+		for name, child := range *p.Properties {
+			v.Properties[name] = child.view()
+		}
+	}
+	if p.AdditionalProperties != nil {
+		if p.AdditionalProperties.Schema != nil {
+			v.AdditionalPropertiesSchema = p.AdditionalProperties.Schema.view()
+		} else {
+			v.AdditionalPropertiesAllowed = p.AdditionalProperties.Allow
+		}
+	}
+	if p.Items != nil && !p.Items.Disallow {
+		if p.Items.Item != nil {
+			v.Items = p.Items.Item.view()
+		} else {
+			for _, alt := range p.Items.AnyOf {
+				v.ItemAlternatives = append(v.ItemAlternatives, alt.view())
+			}
+		}
+	}
+	for _, prefix := range p.PrefixItems {
+		v.PrefixItems = append(v.PrefixItems, prefix.view())
+	}
+	return v
+}
+
 type properties map[string]*property
 
 type property struct {
-	AdditionalProperties *bool       `json:"additionalProperties,omitempty"`
-	Items                *items      `json:"items,omitempty"`
-	Properties           *properties `json:"properties,omitempty"`
-	Type                 jsonType    `json:"type,omitempty"`
-	Required             []string    `json:"required,omitempty"`
+	AdditionalProperties *additionalProperties `json:"additionalProperties,omitempty"`
+	Items                *items                `json:"items,omitempty"`
+	PrefixItems          propertyList          `json:"prefixItems,omitempty"`
+	Properties           *properties           `json:"properties,omitempty"`
+	Type                 jsonTypeList          `json:"type,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Enum                 []interface{}         `json:"enum,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+
+	// Ref points at a $defs entry instead of describing a shape inline. When set,
+	// every other field must be left at its zero value so that omitempty keeps
+	// them out of the marshaled output.
+	Ref string `json:"$ref,omitempty"`
+
+	// observedStrings holds the distinct raw string values seen so far. It feeds
+	// enum/format inference during merge and is never marshaled.
+	observedStrings map[string]bool `json:"-"`
 }
 
 type propertyList []*property
 
-// withoutDuplicates returns a PropertyList without duplicate entries.
-func (p *propertyList) withoutDuplicates() propertyList {
-	unique := make([]*property, 0)
+// withoutDuplicates returns a propertyList in which alternatives that describe
+// the same or an overlapping shape have been merged into a single entry, instead
+// of being kept as separate anyOf alternatives.
+func (p *propertyList) withoutDuplicates(config *SchemaConfig) propertyList {
+	merged := make(propertyList, 0, len(*p))
 	for _, v := range *p {
-		if !v.equalsOneOf(unique) {
-			unique = append(unique, v)
+		combined := false
+		for i, existing := range merged {
+			if mergeableInto(v, existing) {
+				merged[i] = merge(existing, v, config)
+				combined = true
+				break
+			}
+		}
+		if !combined {
+			merged = append(merged, v)
 		}
 	}
-	return unique
+	return merged
 }
 
+// items represents the "items" keyword. The sample-based generation path
+// (merge, addArray) populates AnyOf with one alternative per distinct shape
+// observed in an array for Draft7 output, or Item with every element shape
+// merged into one for Draft202012's ArrayModeList. The reflection-based path
+// (propertyFromType) also populates Item, since a Go slice or array only
+// ever has one element type. Disallow marshals the keyword as the literal
+// false, used alongside property.PrefixItems to forbid elements beyond those
+// enumerated there (Draft202012's ArrayModeTuple).
 type items struct {
-	AnyOf propertyList `json:"anyOf"`
+	AnyOf    propertyList `json:"-"`
+	Item     *property    `json:"-"`
+	Disallow bool         `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler: Disallow takes precedence over Item,
+// which takes precedence over AnyOf, since only one of the three is ever set.
+func (i items) MarshalJSON() ([]byte, error) {
+	if i.Disallow {
+		return json.Marshal(false)
+	}
+	if i.Item != nil {
+		return json.Marshal(i.Item)
+	}
+	return json.Marshal(struct {
+		AnyOf propertyList `json:"anyOf"`
+	}{i.AnyOf})
+}
+
+// children returns every child property of i, regardless of whether it holds
+// an AnyOf list or a single Item. It is empty when Disallow is set.
+func (i *items) children() []*property {
+	if i.Item != nil {
+		return []*property{i.Item}
+	}
+	return i.AnyOf
+}
+
+// additionalProperties represents the "additionalProperties" keyword. The
+// sample-based generation path always sets Allow, a plain bool allowing or
+// forbidding unknown properties. The reflection-based path sets Allow the
+// same way for a struct, and instead sets Schema to the value type of a Go
+// map, since every additional property must match that type.
+type additionalProperties struct {
+	Allow  *bool
+	Schema *property
+}
+
+// additionalPropertiesBool returns an additionalProperties holding a plain
+// bool, matching the sample-based generation path.
+func additionalPropertiesBool(allow bool) *additionalProperties {
+	return &additionalProperties{Allow: &allow}
+}
+
+// MarshalJSON implements json.Marshaler: Schema takes precedence over Allow,
+// since propertyFromType only ever sets one of the two.
+func (a additionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allow)
 }
 
 type jsonType string // Type holds the datatypes known to jsonschema
@@ -156,33 +794,388 @@ const (
 	typeNull    jsonType = "null"
 )
 
-func (p *property) equalsOneOf(others []*property) bool {
-	for _, compare := range others {
-		if p.Type == compare.Type {
-			if compare.Type == typeObject { //deep compare needed in case of objects
-				if !reflect.DeepEqual(p, compare) {
-					continue
-				}
-			}
+// jsonTypeList represents the json-schema "type" keyword. It is usually a single
+// type, but widens to a list once a property has been merged from samples that
+// disagree on the type of a scalar value.
+type jsonTypeList []jsonType
+
+// typeOf returns a jsonTypeList holding exactly the given type.
+func typeOf(t jsonType) jsonTypeList { return jsonTypeList{t} }
+
+// MarshalJSON implements json.Marshaler. A single type is emitted as a plain
+// string, matching every schema produced before multi-type properties existed;
+// only a genuinely disjoint type list is emitted as a JSON array.
+func (t jsonTypeList) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]jsonType(t))
+}
+
+func (p *property) is(t jsonType) bool { return len(p.Type) == 1 && p.Type[0] == t }
+func (p *property) isObject() bool     { return p.is(typeObject) }
+func (p *property) isArray() bool      { return p.is(typeArray) }
+
+// isExtractable reports whether p is eligible for $defs extraction: objects
+// always are, and so are enum-bearing scalars (e.g. a string property with an
+// inferred set of allowed values), since repeating their enum inline across a
+// document is just as noisy as repeating an object shape.
+func isExtractable(p *property) bool { return p.isObject() || len(p.Enum) > 0 }
+
+// mergeableInto reports whether p can be folded into other as a single anyOf
+// alternative: objects merge if they share at least one property key, arrays
+// always merge, and scalars always merge (their types combine, see mergeScalars).
+func mergeableInto(p, other *property) bool {
+	switch {
+	case p.isObject() && other.isObject():
+		return sharesKey(p, other)
+	case p.isArray() && other.isArray():
+		return true
+	case !p.isObject() && !p.isArray() && !other.isObject() && !other.isArray():
+		return true
+	default:
+		return false
+	}
+}
+
+func sharesKey(a, b *property) bool {
+	if a.Properties == nil || b.Properties == nil {
+		return false
+	}
+	for k := range *a.Properties {
+		if _, ok := (*b.Properties)[k]; ok {
 			return true
 		}
 	}
 	return false
 }
 
+// merge combines two properties describing the same location - be it the same
+// key across two samples, or two elements of the same array - into a single
+// property that accepts everything either one of them accepts. config may be
+// nil, in which case no optional inference (enum/format/min/max) is applied.
+func merge(a, b *property, config *SchemaConfig) *property {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	switch {
+	case a.isObject() && b.isObject():
+		return mergeObjects(a, b, config)
+	case a.isArray() && b.isArray():
+		return mergeArrays(a, b, config)
+	default:
+		return mergeScalars(a, b, config)
+	}
+}
+
+func mergeObjects(a, b *property, config *SchemaConfig) *property {
+	result := &property{Type: typeOf(typeObject)}
+
+	result.AdditionalProperties = a.AdditionalProperties
+	if result.AdditionalProperties == nil {
+		result.AdditionalProperties = b.AdditionalProperties
+	}
+
+	merged := make(properties)
+	if a.Properties != nil {
+		for k, v := range *a.Properties {
+			merged[k] = v
+		}
+	}
+	if b.Properties != nil {
+		for k, v := range *b.Properties {
+			if existing, ok := merged[k]; ok {
+				merged[k] = merge(existing, v, config)
+			} else {
+				merged[k] = v
+			}
+		}
+	}
+	result.Properties = &merged
+	result.Required = intersectStrings(a.Required, b.Required)
+	return result
+}
+
+func mergeArrays(a, b *property, config *SchemaConfig) *property {
+	combined := propertyList{}
+	if a.Items != nil {
+		combined = append(combined, a.Items.children()...)
+	}
+	combined = append(combined, a.PrefixItems...)
+	if b.Items != nil {
+		combined = append(combined, b.Items.children()...)
+	}
+	combined = append(combined, b.PrefixItems...)
+
+	// combined concatenates the elements of two arrays, so its order says
+	// nothing about positions: render it as a merged list, never a tuple.
+	if len(combined) > 0 && effectiveDraft(config) == Draft202012 && effectiveArrayMode(config) == ArrayModeTuple {
+		listConfig := *config
+		listConfig.ArrayMode = ArrayModeList
+		config = &listConfig
+	}
+
+	result := &property{Type: typeOf(typeArray)}
+	result.Items, result.PrefixItems = buildArrayItems(combined, config)
+	return result
+}
+
+// buildArrayItems decides how to render an array's element schemas: Draft7
+// output (the default) always keeps the legacy items.anyOf form. Draft202012
+// output follows ArrayMode: ArrayModeTuple renders prefixItems with items:
+// false, but only when every element is a distinct shape (otherwise it falls
+// back to ArrayModeList); ArrayModeList merges every element into a single
+// items schema.
+func buildArrayItems(elements propertyList, config *SchemaConfig) (*items, propertyList) {
+	if effectiveDraft(config) != Draft202012 {
+		return &items{AnyOf: elements.withoutDuplicates(config)}, nil
+	}
+
+	if effectiveArrayMode(config) == ArrayModeTuple && allDistinct(elements) {
+		return &items{Disallow: true}, elements
+	}
+
+	var merged *property
+	for _, e := range elements {
+		merged = merge(merged, e, config)
+	}
+	if merged == nil {
+		return nil, nil
+	}
+	return &items{Item: merged}, nil
+}
+
+// allDistinct reports whether every element of elements has a different
+// shape, i.e. no two elements hash identically. Unlike withoutDuplicates
+// (which also folds e.g. an int and a string together into one widened
+// scalar alternative), this only looks at exact shape equality, since that
+// is what makes an array "tuple-shaped" rather than a homogeneous list.
+func allDistinct(elements propertyList) bool {
+	seen := make(map[string]bool, len(elements))
+	for _, e := range elements {
+		h := shapeHash(e)
+		if seen[h] {
+			return false
+		}
+		seen[h] = true
+	}
+	return true
+}
+
+// mergeScalars combines the types of two non-object, non-array properties,
+// widening integer+number to number and otherwise collecting disjoint types
+// into a "type": [...] list. If config enables it, it also widens the observed
+// minimum/maximum of numeric properties and the enum/format of string properties.
+func mergeScalars(a, b *property, config *SchemaConfig) *property {
+	types := map[jsonType]bool{}
+	for _, t := range append(append(jsonTypeList{}, a.Type...), b.Type...) {
+		types[t] = true
+	}
+	if types[typeInteger] && types[typeNumber] {
+		delete(types, typeInteger)
+	}
+
+	sorted := make(jsonTypeList, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	result := &property{Type: sorted}
+
+	if config == nil {
+		return result
+	}
+
+	if config.MinMaxNumeric && len(sorted) == 1 && (sorted[0] == typeInteger || sorted[0] == typeNumber) {
+		result.Minimum = lowerOf(a.Minimum, b.Minimum)
+		result.Maximum = higherOf(a.Maximum, b.Maximum)
+	}
+
+	if config.InferStringConstraints && len(sorted) == 1 && sorted[0] == typeString {
+		result.observedStrings = unionStringSets(a.observedStrings, b.observedStrings)
+		applyStringConstraints(result, config)
+	}
+
+	return result
+}
+
+// lowerOf returns the smaller of a and b, treating a nil pointer as absent.
+func lowerOf(a, b *float64) *float64 {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if *a < *b {
+		return a
+	}
+	return b
+}
+
+// higherOf returns the larger of a and b, treating a nil pointer as absent.
+func higherOf(a, b *float64) *float64 {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if *a > *b {
+		return a
+	}
+	return b
+}
+
+// numericValue converts a Go int or float leaf value to float64 for use in
+// Minimum/Maximum tracking.
+func numericValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int8:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func unionStringSets(a, b map[string]bool) map[string]bool {
+	union := make(map[string]bool, len(a)+len(b))
+	for v := range a {
+		union[v] = true
+	}
+	for v := range b {
+		union[v] = true
+	}
+	return union
+}
+
+const defaultMaxEnumValues = 20
+
+// applyStringConstraints sets p.Enum and p.Format from p.observedStrings
+// according to config.
+func applyStringConstraints(p *property, config *SchemaConfig) {
+	maxEnumValues := config.MaxEnumValues
+	if maxEnumValues == 0 {
+		maxEnumValues = defaultMaxEnumValues
+	}
+	if len(p.observedStrings) > 0 && len(p.observedStrings) <= maxEnumValues {
+		values := make([]string, 0, len(p.observedStrings))
+		for v := range p.observedStrings {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		p.Enum = make([]interface{}, len(values))
+		for i, v := range values {
+			p.Enum[i] = v
+		}
+	} else {
+		p.Enum = nil
+	}
+
+	detectors := config.FormatDetectors
+	if detectors == nil {
+		detectors = defaultFormatDetectors
+	}
+	p.Format = detectFormat(p.observedStrings, detectors)
+}
+
+// detectFormat returns the name of the first detector in detectors that every
+// value in observed matches, or "" if none does (or observed is empty).
+func detectFormat(observed map[string]bool, detectors []func(string) (string, bool)) string {
+	if len(observed) == 0 {
+		return ""
+	}
+	for _, detect := range detectors {
+		name := ""
+		allMatch := true
+		for v := range observed {
+			f, ok := detect(v)
+			if !ok {
+				allMatch = false
+				break
+			}
+			name = f
+		}
+		if allMatch {
+			return name
+		}
+	}
+	return ""
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// defaultFormatDetectors holds the built-in FormatDetectors used when
+// SchemaConfig.FormatDetectors is nil.
+var defaultFormatDetectors = []func(string) (string, bool){
+	func(v string) (string, bool) {
+		_, err := time.Parse(time.RFC3339, v)
+		return "date-time", err == nil
+	},
+	func(v string) (string, bool) {
+		_, err := mail.ParseAddress(v)
+		return "email", err == nil
+	},
+	func(v string) (string, bool) {
+		u, err := url.ParseRequestURI(v)
+		return "uri", err == nil && u.Scheme != ""
+	},
+	func(v string) (string, bool) {
+		return "uuid", uuidPattern.MatchString(v)
+	},
+	func(v string) (string, bool) {
+		ip := net.ParseIP(v)
+		return "ipv4", ip != nil && strings.Contains(v, ".")
+	},
+	func(v string) (string, bool) {
+		ip := net.ParseIP(v)
+		return "ipv6", ip != nil && strings.Contains(v, ":")
+	},
+}
+
+// intersectStrings returns the values present in both a and b, preserving a's order.
+func intersectStrings(a, b []string) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	inB := make(map[string]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	result := make([]string, 0, len(a))
+	for _, v := range a {
+		if inB[v] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 func (p *property) requireExactlyAllKeysFromMap(m map[string]interface{}) {
 	p.Required = make([]string, 0, len(m))
 	for k := range m {
 		p.Required = append(p.Required, k)
 	}
+	sort.Strings(p.Required)
 }
 
 func newProperty(data interface{}, config *SchemaConfig) (*property, error) {
 	p := &property{}
 
-	// helper function to set pointers to primitive types
-	pbool := func(b bool) *bool { return &b }
-
 	// pre-convert keys to strings to prevent code duplication below
 	switch v := data.(type) {
 	case map[interface{}]interface{}:
@@ -198,34 +1191,48 @@ func newProperty(data interface{}, config *SchemaConfig) (*property, error) {
 		if config.RequireAllProperties {
 			p.requireExactlyAllKeysFromMap(v)
 		}
-		p.Type = typeObject
+		p.Type = typeOf(typeObject)
 		if !config.AdditionalProperties { // default is true, so only set it in other case
-			p.AdditionalProperties = pbool(false)
+			p.AdditionalProperties = additionalPropertiesBool(false)
 		}
 		if err := p.addObject(v, config); err != nil {
 			return nil, err
 		}
 		return p, nil
 	case []interface{}:
-		p.Type = typeArray
+		p.Type = typeOf(typeArray)
 		if err := p.addArray(v, config); err != nil {
 			return nil, err
 		}
 		return p, nil
 	case string:
-		p.Type = typeString
+		p.Type = typeOf(typeString)
+		if config.InferStringConstraints {
+			p.observedStrings = map[string]bool{v: true}
+			applyStringConstraints(p, config)
+		}
 		return p, nil
 	case int, int8, int16, int32, int64:
-		p.Type = typeInteger
+		p.Type = typeOf(typeInteger)
+		if config.MinMaxNumeric {
+			n := numericValue(v)
+			p.Minimum = &n
+			p.Maximum = &n
+		}
 		return p, nil
 	case float32, float64:
-		p.Type = typeNumber
+		p.Type = typeOf(typeNumber)
+		if config.MinMaxNumeric {
+			n := numericValue(v)
+			p.Minimum = &n
+			p.Maximum = &n
+		}
 		return p, nil
 	case bool:
-		p.Type = typeBoolean
+		p.Type = typeOf(typeBoolean)
 		return p, nil
 	case nil:
-		p.Type = typeNull
+		p.Type = typeOf(typeNull)
 		return p, nil
 	default:
 		return nil, fmt.Errorf("unexpected type %v of data", reflect.TypeOf(data))
@@ -260,16 +1267,222 @@ func (p *property) addObject(values map[string]interface{}, config *SchemaConfig
 }
 
 func (p *property) addArray(values []interface{}, config *SchemaConfig) error {
-	if p.Items == nil {
-		p.Items = &items{AnyOf: []*property{}}
-	}
+	elements := propertyList{}
 	for _, v := range values {
 		item, err := newProperty(v, config)
 		if err != nil {
 			return err
 		}
-		p.Items.AnyOf = append(p.Items.AnyOf, item)
+		elements = append(elements, item)
 	}
-	p.Items.AnyOf = p.Items.AnyOf.withoutDuplicates()
+	p.Items, p.PrefixItems = buildArrayItems(elements, config)
 	return nil
 }
+
+// extractDefinitions walks root and any pre-existing entries of defs, moves
+// every object shape that occurs at two or more locations across both into a
+// $defs entry, and rewrites each occurrence (in root and in the pre-existing
+// defs themselves) as a $ref to that entry. New entries are added to defs;
+// pre-existing entries are kept under their original name, never replaced.
+func extractDefinitions(root *property, defs map[string]*property, config *SchemaConfig) {
+	existing := make([]string, 0, len(defs))
+	for name := range defs {
+		existing = append(existing, name)
+	}
+	sort.Strings(existing)
+
+	counts := map[string]int{}
+	firstPath := map[string][]string{}
+	collectShapeCounts(root, nil, counts, firstPath)
+	for _, name := range existing {
+		collectShapeCounts(defs[name], []string{"$defs", name}, counts, firstPath)
+	}
+
+	naming := config.DefinitionNaming
+	if naming == nil {
+		naming = defaultDefinitionName
+	}
+
+	hashToName := map[string]string{}
+	for _, name := range existing {
+		if p := defs[name]; isExtractable(p) {
+			hashToName[shapeHash(p)] = name
+		}
+	}
+
+	root.extractDefs(nil, counts, firstPath, naming, defs, hashToName)
+	for _, name := range existing {
+		defs[name].extractDefs(nil, counts, firstPath, naming, defs, hashToName)
+	}
+}
+
+// collectShapeCounts records, for every object subtree of p, how many times its
+// shape occurs in the whole tree and the path of its first occurrence.
+func collectShapeCounts(p *property, path []string, counts map[string]int, firstPath map[string][]string) {
+	if isExtractable(p) {
+		h := shapeHash(p)
+		counts[h]++
+		if _, ok := firstPath[h]; !ok {
+			firstPath[h] = append([]string{}, path...)
+		}
+	}
+	if p.Properties != nil {
+		for _, k := range sortedKeys(p.Properties) {
+			collectShapeCounts((*p.Properties)[k], append(append([]string{}, path...), k), counts, firstPath)
+		}
+	}
+	if p.Items != nil {
+		if p.Items.Item != nil {
+			collectShapeCounts(p.Items.Item, append(append([]string{}, path...), "items"), counts, firstPath)
+		} else {
+			for i, item := range p.Items.AnyOf {
+				collectShapeCounts(item, append(append([]string{}, path...), strconv.Itoa(i)), counts, firstPath)
+			}
+		}
+	}
+	for i, item := range p.PrefixItems {
+		collectShapeCounts(item, append(append([]string{}, path...), "prefixItems", strconv.Itoa(i)), counts, firstPath)
+	}
+}
+
+// extractDefs rewrites every child of p (but never p itself) that qualifies for
+// extraction into a $ref, recursing depth-first so that a def can itself contain
+// other defs. Each child is matched against counts before its own children
+// are rewritten, since counts were collected from the tree before any rewrite.
+func (p *property) extractDefs(path []string, counts map[string]int, firstPath map[string][]string, naming func([]string) string, defs map[string]*property, hashToName map[string]string) {
+	if p.Properties != nil {
+		for _, k := range sortedKeys(p.Properties) {
+			childPath := append(append([]string{}, path...), k)
+			child := (*p.Properties)[k]
+			replacement := refIfRepeated(child, childPath, counts, firstPath, naming, defs, hashToName)
+			child.extractDefs(childPath, counts, firstPath, naming, defs, hashToName)
+			(*p.Properties)[k] = replacement
+		}
+	}
+	if p.Items != nil {
+		if p.Items.Item != nil {
+			itemPath := append(append([]string{}, path...), "items")
+			replacement := refIfRepeated(p.Items.Item, itemPath, counts, firstPath, naming, defs, hashToName)
+			p.Items.Item.extractDefs(itemPath, counts, firstPath, naming, defs, hashToName)
+			p.Items.Item = replacement
+		} else {
+			for i, item := range p.Items.AnyOf {
+				itemPath := append(append([]string{}, path...), strconv.Itoa(i))
+				replacement := refIfRepeated(item, itemPath, counts, firstPath, naming, defs, hashToName)
+				item.extractDefs(itemPath, counts, firstPath, naming, defs, hashToName)
+				p.Items.AnyOf[i] = replacement
+			}
+		}
+	}
+	for i, item := range p.PrefixItems {
+		itemPath := append(append([]string{}, path...), "prefixItems", strconv.Itoa(i))
+		replacement := refIfRepeated(item, itemPath, counts, firstPath, naming, defs, hashToName)
+		item.extractDefs(itemPath, counts, firstPath, naming, defs, hashToName)
+		p.PrefixItems[i] = replacement
+	}
+}
+
+// refIfRepeated returns a $ref to p's $defs entry if p's shape occurs more than
+// once in the document, registering the entry on first use; otherwise it
+// returns p unchanged.
+func refIfRepeated(p *property, path []string, counts map[string]int, firstPath map[string][]string, naming func([]string) string, defs map[string]*property, hashToName map[string]string) *property {
+	if !isExtractable(p) {
+		return p
+	}
+	h := shapeHash(p)
+	if counts[h] < 2 {
+		return p
+	}
+	name, ok := hashToName[h]
+	if !ok {
+		name = naming(firstPath[h])
+		hashToName[h] = name
+		defs[name] = p
+	}
+	return &property{Ref: "#/$defs/" + name}
+}
+
+// defaultDefinitionName joins the path segments of a $defs entry's first
+// occurrence with "_", e.g. []string{"users", "0", "address"} becomes
+// "users_0_address".
+func defaultDefinitionName(path []string) string {
+	if len(path) == 0 {
+		return "root"
+	}
+	return strings.Join(path, "_")
+}
+
+func sortedKeys(p *properties) []string {
+	keys := make([]string, 0, len(*p))
+	for k := range *p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shapeHash returns a stable hash of the parts of p that determine its shape
+// (Type, Properties, Items, PrefixItems, Required, AdditionalProperties, and
+// the Enum, Format, Minimum, and Maximum constraints), so that two
+// structurally identical subtrees hash identically regardless of map iteration
+// order.
+func shapeHash(p *property) string {
+	sum := sha256.Sum256(canonicalize(p))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalProperty mirrors the shape-defining subset of property's fields.
+// Properties is a map so encoding/json sorts its keys, and Items preserves
+// array order, making the resulting JSON a stable canonicalization of a
+// property's shape.
+type canonicalProperty struct {
+	Type                 jsonTypeList               `json:"type,omitempty"`
+	Properties           map[string]json.RawMessage `json:"properties,omitempty"`
+	Items                []json.RawMessage          `json:"items,omitempty"`
+	PrefixItems          []json.RawMessage          `json:"prefixItems,omitempty"`
+	Required             []string                   `json:"required,omitempty"`
+	AdditionalProperties *additionalProperties      `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}              `json:"enum,omitempty"`
+	Format               string                     `json:"format,omitempty"`
+	Minimum              *float64                   `json:"minimum,omitempty"`
+	Maximum              *float64                   `json:"maximum,omitempty"`
+}
+
+func canonicalize(p *property) []byte {
+	c := canonicalProperty{
+		Type:                 p.Type,
+		AdditionalProperties: p.AdditionalProperties,
+		Enum:                 p.Enum,
+		Format:               p.Format,
+		Minimum:              p.Minimum,
+		Maximum:              p.Maximum,
+	}
+	c.Required = append([]string{}, p.Required...)
+	sort.Strings(c.Required)
+
+	if p.Properties != nil {
+		c.Properties = make(map[string]json.RawMessage, len(*p.Properties))
+		for k, v := range *p.Properties {
+			c.Properties[k] = canonicalize(v)
+		}
+	}
+	if p.Items != nil {
+		children := p.Items.children()
+		c.Items = make([]json.RawMessage, len(children))
+		for i, item := range children {
+			c.Items[i] = canonicalize(item)
+		}
+	}
+	if len(p.PrefixItems) > 0 {
+		c.PrefixItems = make([]json.RawMessage, len(p.PrefixItems))
+		for i, item := range p.PrefixItems {
+			c.PrefixItems[i] = canonicalize(item)
+		}
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		panic(fmt.Sprintf("canonicalize: %v", err)) // unreachable: canonicalProperty only holds marshalable values
+	}
+	return b
+}
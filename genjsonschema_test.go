@@ -1,11 +1,14 @@
 package genjsonschema
 
 import (
+	"bytes"
 	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"gopkg.in/yaml.v2"
 )
 
@@ -13,10 +16,6 @@ func stubSchema(id string, p property) *schema {
 	return &schema{ID: id, JsonSchemaRef: jsonSchemaRef, property: p}
 }
 
-func pbool(v bool) *bool {
-	return &v
-}
-
 func TestSchemaGeneration(t *testing.T) {
 	tests := []struct {
 		name                 string
@@ -31,11 +30,11 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeArray,
+				Type: typeOf(typeArray),
 				Items: &items{
 					AnyOf: propertyList{
 						&property{
-							Type: typeInteger,
+							Type: typeOf(typeInteger),
 						},
 					},
 				},
@@ -46,10 +45,10 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"foo": &property{
-						Type: typeString,
+						Type: typeOf(typeString),
 					},
 				},
 			}),
@@ -65,26 +64,26 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"str": &property{
-						Type: typeString,
+						Type: typeOf(typeString),
 					},
 					"int": &property{
-						Type: typeInteger,
+						Type: typeOf(typeInteger),
 					},
 					"float": &property{
-						Type: typeNumber,
+						Type: typeOf(typeNumber),
 					},
 					"nil": &property{
-						Type: typeNull,
+						Type: typeOf(typeNull),
 					},
 					"obj": &property{
-						Type:       typeObject,
+						Type:       typeOf(typeObject),
 						Properties: &properties{},
 					},
 					"arr": &property{
-						Type:  typeArray,
+						Type:  typeOf(typeArray),
 						Items: &items{AnyOf: propertyList{}},
 					},
 				},
@@ -97,13 +96,13 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"foo": &property{
-						Type: typeObject,
+						Type: typeOf(typeObject),
 						Properties: &properties{
 							"bar": &property{
-								Type: typeInteger,
+								Type: typeOf(typeInteger),
 							},
 						},
 					},
@@ -117,15 +116,15 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			true,
 			stubSchema("", property{
-				Type:     typeObject,
+				Type:     typeOf(typeObject),
 				Required: []string{"foo"},
 				Properties: &properties{
 					"foo": &property{
-						Type:     typeObject,
+						Type:     typeOf(typeObject),
 						Required: []string{"bar"},
 						Properties: &properties{
 							"bar": &property{
-								Type: typeInteger,
+								Type: typeOf(typeInteger),
 							},
 						},
 					},
@@ -139,15 +138,15 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type:                 typeObject,
+				Type:                 typeOf(typeObject),
 				AdditionalProperties: nil, // true is default and thus we expect nil to save schema size
 				Properties: &properties{
 					"foo": &property{
-						Type:                 typeObject,
+						Type:                 typeOf(typeObject),
 						AdditionalProperties: nil,
 						Properties: &properties{
 							"bar": &property{
-								Type: typeInteger,
+								Type: typeOf(typeInteger),
 							},
 						},
 					},
@@ -161,15 +160,15 @@ func TestSchemaGeneration(t *testing.T) {
 			false,
 			false,
 			stubSchema("", property{
-				Type:                 typeObject,
-				AdditionalProperties: pbool(false), // false must be declared explicitely by the schema
+				Type:                 typeOf(typeObject),
+				AdditionalProperties: additionalPropertiesBool(false), // false must be declared explicitely by the schema
 				Properties: &properties{
 					"foo": &property{
-						Type:                 typeObject,
-						AdditionalProperties: pbool(false),
+						Type:                 typeOf(typeObject),
+						AdditionalProperties: additionalPropertiesBool(false),
 						Properties: &properties{
 							"bar": &property{
-								Type: typeInteger,
+								Type: typeOf(typeInteger),
 							},
 						},
 					},
@@ -182,13 +181,13 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"items": &property{
-						Type: typeArray,
+						Type: typeOf(typeArray),
 						Items: &items{
 							AnyOf: propertyList{
-								&property{Type: typeInteger},
+								&property{Type: typeOf(typeInteger)},
 							},
 						},
 					},
@@ -201,24 +200,24 @@ func TestSchemaGeneration(t *testing.T) {
 			true,
 			false,
 			stubSchema("", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"items": &property{
-						Type: typeArray,
+						Type: typeOf(typeArray),
 						Items: &items{ //items from above with removed indices
 							AnyOf: propertyList{
-								&property{Type: typeInteger},
-								&property{Type: typeObject,
+								&property{Type: typeOf(typeInteger)},
+								&property{Type: typeOf(typeObject),
 									Properties: &properties{
 										"foo": &property{
-											Type: typeInteger,
+											Type: typeOf(typeInteger),
 										},
 									},
 								},
-								&property{Type: typeObject,
+								&property{Type: typeOf(typeObject),
 									Properties: &properties{
 										"bar": &property{
-											Type: typeInteger,
+											Type: typeOf(typeInteger),
 										},
 									},
 								},
@@ -241,7 +240,7 @@ func TestSchemaGeneration(t *testing.T) {
 			if err != nil {
 				t.Error(err)
 			}
-			if delta := cmp.Diff(got, v.want, cmp.AllowUnexported(schema{})); delta != "" {
+			if delta := cmp.Diff(got, v.want, cmp.AllowUnexported(schema{}, property{})); delta != "" {
 				t.Logf("Given %s got %v but wanted %v\nDelta:\n", v.given, got, v.want)
 				t.Error(delta)
 			}
@@ -309,10 +308,10 @@ func TestSerialization(t *testing.T) {
 		{
 			name: "single object with attribute foo and id bar",
 			given: stubSchema("bar", property{
-				Type: typeObject,
+				Type: typeOf(typeObject),
 				Properties: &properties{
 					"foo": &property{
-						Type: typeString,
+						Type: typeOf(typeString),
 					},
 				},
 			}),
@@ -331,12 +330,12 @@ func TestSerialization(t *testing.T) {
 		{
 			name: "single object, all attributes required no addtional attributes",
 			given: stubSchema("", property{
-				Type:                 typeObject,
-				AdditionalProperties: pbool(false),
+				Type:                 typeOf(typeObject),
+				AdditionalProperties: additionalPropertiesBool(false),
 				Required:             []string{"foo"},
 				Properties: &properties{
 					"foo": &property{
-						Type: typeString,
+						Type: typeOf(typeString),
 					},
 				},
 			}),
@@ -379,6 +378,809 @@ func TestSerialization(t *testing.T) {
 	}
 }
 
+func TestGenerateFromJSONSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{
+			name: "required only if present in every sample",
+			samples: []string{
+				`{"foo": "a", "bar": 1}`,
+				`{"foo": "b"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["foo"],
+				"properties": {
+					"foo": {"type": "string"},
+					"bar": {"type": "integer"}
+				}
+			}`,
+		},
+		{
+			name: "integer and number widen to number",
+			samples: []string{
+				`{"val": 1}`,
+				`{"val": 1.5}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["val"],
+				"properties": {
+					"val": {"type": "number"}
+				}
+			}`,
+		},
+		{
+			name: "disjoint scalar types combine into a type list",
+			samples: []string{
+				`{"val": "a"}`,
+				`{"val": true}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["val"],
+				"properties": {
+					"val": {"type": ["boolean", "string"]}
+				}
+			}`,
+		},
+		{
+			name: "overlapping object shapes in an array merge into one alternative",
+			samples: []string{
+				`{"items": [{"foo": 1}]}`,
+				`{"items": [{"foo": 2, "bar": "x"}]}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["items"],
+				"properties": {
+					"items": {
+						"type": "array",
+						"items": {
+							"anyOf": [
+								{
+									"type": "object",
+									"additionalProperties": false,
+									"required": ["foo"],
+									"properties": {
+										"foo": {"type": "integer"},
+										"bar": {"type": "string"}
+									}
+								}
+							]
+						}
+					}
+				}
+			}`,
+		},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			samples := make([][]byte, len(v.samples))
+			for i, s := range v.samples {
+				samples[i] = []byte(s)
+			}
+
+			got, err := GenerateFromJSONSamples(samples, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotAsMap := make(map[string]interface{})
+			wantAsMap := make(map[string]interface{})
+			if err := json.Unmarshal(got, &gotAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(v.want), &wantAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if delta := cmp.Diff(gotAsMap, wantAsMap); delta != "" {
+				t.Logf("got %s", got)
+				t.Error(delta)
+			}
+		})
+	}
+}
+
+func TestGenerateFromJSONSamplesRequiresAtLeastOneSample(t *testing.T) {
+	_, err := GenerateFromJSONSamples(nil, nil)
+	if err == nil {
+		t.Error("expected error for empty sample list")
+	}
+}
+
+func TestExtractDefinitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		given  string
+		naming func(path []string) string
+		want   string
+	}{
+		{
+			name:  "repeated object shape is extracted and ref'd from every occurrence",
+			given: `{"a": {"x": 1}, "b": {"x": 2}}`,
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["a", "b"],
+				"properties": {
+					"a": {"$ref": "#/$defs/a"},
+					"b": {"$ref": "#/$defs/a"}
+				},
+				"$defs": {
+					"a": {
+						"type": "object",
+						"additionalProperties": false,
+						"required": ["x"],
+						"properties": {"x": {"type": "integer"}}
+					}
+				}
+			}`,
+		},
+		{
+			name:  "object shapes that occur only once stay inlined",
+			given: `{"a": {"x": 1}, "b": {"y": 1}}`,
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["a", "b"],
+				"properties": {
+					"a": {
+						"type": "object",
+						"additionalProperties": false,
+						"required": ["x"],
+						"properties": {"x": {"type": "integer"}}
+					},
+					"b": {
+						"type": "object",
+						"additionalProperties": false,
+						"required": ["y"],
+						"properties": {"y": {"type": "integer"}}
+					}
+				}
+			}`,
+		},
+		{
+			name:   "naming can be overridden",
+			given:  `{"a": {"x": 1}, "b": {"x": 2}}`,
+			naming: func(path []string) string { return "custom_" + path[0] },
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["a", "b"],
+				"properties": {
+					"a": {"$ref": "#/$defs/custom_a"},
+					"b": {"$ref": "#/$defs/custom_a"}
+				},
+				"$defs": {
+					"custom_a": {
+						"type": "object",
+						"additionalProperties": false,
+						"required": ["x"],
+						"properties": {"x": {"type": "integer"}}
+					}
+				}
+			}`,
+		},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			cfg := NewDefaultSchemaConfig()
+			cfg.ExtractDefinitions = true
+			cfg.DefinitionNaming = v.naming
+
+			got, err := GenerateFromJSON([]byte(v.given), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotAsMap := make(map[string]interface{})
+			wantAsMap := make(map[string]interface{})
+			if err := json.Unmarshal(got, &gotAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(v.want), &wantAsMap); err != nil {
+				t.Fatal(err)
+			}
+			// Compare "required" lists as sets rather than ordered lists, so the
+			// assertion doesn't depend on the order the generator lists them in.
+			sortStrings := cmpopts.SortSlices(func(a, b interface{}) bool {
+				as, aok := a.(string)
+				bs, bok := b.(string)
+				return aok && bok && as < bs
+			})
+			if delta := cmp.Diff(gotAsMap, wantAsMap, sortStrings); delta != "" {
+				t.Logf("got %s", got)
+				t.Error(delta)
+			}
+		})
+	}
+}
+
+func TestInferStringConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{
+			name: "small set of observed values becomes an enum",
+			samples: []string{
+				`{"status": "open"}`,
+				`{"status": "closed"}`,
+				`{"status": "open"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["status"],
+				"properties": {
+					"status": {"type": "string", "enum": ["closed", "open"]}
+				}
+			}`,
+		},
+		{
+			name: "values beyond MaxEnumValues do not become an enum",
+			samples: []string{
+				`{"id": "a"}`,
+				`{"id": "b"}`,
+				`{"id": "c"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["id"],
+				"properties": {
+					"id": {"type": "string"}
+				}
+			}`,
+		},
+		{
+			name: "date-time format is detected",
+			samples: []string{
+				`{"at": "2023-01-02T15:04:05Z"}`,
+				`{"at": "2023-06-07T08:09:10Z"}`,
+				`{"at": "2023-11-12T13:14:15Z"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["at"],
+				"properties": {
+					"at": {"type": "string", "format": "date-time"}
+				}
+			}`,
+		},
+		{
+			name: "email format is detected",
+			samples: []string{
+				`{"contact": "a@example.com"}`,
+				`{"contact": "b@example.com"}`,
+				`{"contact": "c@example.com"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["contact"],
+				"properties": {
+					"contact": {"type": "string", "format": "email"}
+				}
+			}`,
+		},
+		{
+			name: "uri format is detected",
+			samples: []string{
+				`{"link": "https://example.com/a"}`,
+				`{"link": "https://example.com/b"}`,
+				`{"link": "https://example.com/c"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["link"],
+				"properties": {
+					"link": {"type": "string", "format": "uri"}
+				}
+			}`,
+		},
+		{
+			name: "uuid format is detected",
+			samples: []string{
+				`{"id": "123e4567-e89b-12d3-a456-426614174000"}`,
+				`{"id": "123e4567-e89b-12d3-a456-426614174001"}`,
+				`{"id": "123e4567-e89b-12d3-a456-426614174002"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["id"],
+				"properties": {
+					"id": {"type": "string", "format": "uuid"}
+				}
+			}`,
+		},
+		{
+			name: "ipv4 format is detected",
+			samples: []string{
+				`{"addr": "192.168.0.1"}`,
+				`{"addr": "10.0.0.1"}`,
+				`{"addr": "172.16.0.1"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["addr"],
+				"properties": {
+					"addr": {"type": "string", "format": "ipv4"}
+				}
+			}`,
+		},
+		{
+			name: "ipv6 format is detected",
+			samples: []string{
+				`{"addr": "::1"}`,
+				`{"addr": "2001:db8::1"}`,
+				`{"addr": "2001:db8::2"}`,
+			},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["addr"],
+				"properties": {
+					"addr": {"type": "string", "format": "ipv6"}
+				}
+			}`,
+		},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			samples := make([][]byte, len(v.samples))
+			for i, s := range v.samples {
+				samples[i] = []byte(s)
+			}
+
+			cfg := NewDefaultSchemaConfig()
+			cfg.InferStringConstraints = true
+			cfg.MaxEnumValues = 2
+
+			got, err := GenerateFromJSONSamples(samples, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotAsMap := make(map[string]interface{})
+			wantAsMap := make(map[string]interface{})
+			if err := json.Unmarshal(got, &gotAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(v.want), &wantAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if delta := cmp.Diff(gotAsMap, wantAsMap); delta != "" {
+				t.Logf("got %s", got)
+				t.Error(delta)
+			}
+		})
+	}
+}
+
+func TestMinMaxNumeric(t *testing.T) {
+	samples := [][]byte{
+		[]byte(`{"age": 5}`),
+		[]byte(`{"age": 42}`),
+		[]byte(`{"age": 17}`),
+	}
+	want := `{
+		"$schema": "http://json-schema.org/draft-07/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["age"],
+		"properties": {
+			"age": {"type": "integer", "minimum": 5, "maximum": 42}
+		}
+	}`
+
+	cfg := NewDefaultSchemaConfig()
+	cfg.MinMaxNumeric = true
+
+	got, err := GenerateFromJSONSamples(samples, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotAsMap := make(map[string]interface{})
+	wantAsMap := make(map[string]interface{})
+	if err := json.Unmarshal(got, &gotAsMap); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantAsMap); err != nil {
+		t.Fatal(err)
+	}
+	if delta := cmp.Diff(gotAsMap, wantAsMap); delta != "" {
+		t.Logf("got %s", got)
+		t.Error(delta)
+	}
+}
+
+func TestInferredEnumSurvivesDefinitionExtraction(t *testing.T) {
+	samples := [][]byte{
+		[]byte(`{"a": "open", "b": "closed"}`),
+		[]byte(`{"a": "closed", "b": "open"}`),
+	}
+	want := `{
+		"$schema": "http://json-schema.org/draft-07/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["a", "b"],
+		"properties": {
+			"a": {"$ref": "#/$defs/a"},
+			"b": {"$ref": "#/$defs/a"}
+		},
+		"$defs": {
+			"a": {"type": "string", "enum": ["closed", "open"]}
+		}
+	}`
+
+	cfg := NewDefaultSchemaConfig()
+	cfg.InferStringConstraints = true
+	cfg.ExtractDefinitions = true
+
+	got, err := GenerateFromJSONSamples(samples, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotAsMap := make(map[string]interface{})
+	wantAsMap := make(map[string]interface{})
+	if err := json.Unmarshal(got, &gotAsMap); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantAsMap); err != nil {
+		t.Fatal(err)
+	}
+	// Compare "required" lists as sets rather than ordered lists, so the
+	// assertion doesn't depend on the order the generator lists them in.
+	sortStrings := cmpopts.SortSlices(func(a, b interface{}) bool {
+		as, aok := a.(string)
+		bs, bok := b.(string)
+		return aok && bok && as < bs
+	})
+	if delta := cmp.Diff(gotAsMap, wantAsMap, sortStrings); delta != "" {
+		t.Logf("got %s", got)
+		t.Error(delta)
+	}
+}
+
+func TestGenerateFromType(t *testing.T) {
+	type Embedded struct {
+		Inner string `json:"inner"`
+	}
+	type Person struct {
+		Embedded
+		Name     string            `json:"name"`
+		Age      int               `json:"age,omitempty"`
+		Tags     []string          `json:"tags"`
+		Nickname *string           `json:"nickname,omitempty"`
+		Meta     map[string]string `json:"meta"`
+		secret   string            // unexported, must not reach the schema
+		Ignored  string            `json:"-"`
+	}
+	_ = Person{}.secret
+
+	tests := []struct {
+		name  string
+		given interface{}
+		want  string
+	}{
+		{
+			name:  "scalars",
+			given: "",
+			want:  `{"$schema": "http://json-schema.org/draft-07/schema", "type": "string"}`,
+		},
+		{
+			name:  "slice becomes array with a single items schema",
+			given: []int{},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "array",
+				"items": {"type": "integer"}
+			}`,
+		},
+		{
+			name:  "struct with embedded fields, tags, pointers, and maps",
+			given: Person{},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["inner", "name", "tags", "meta"],
+				"properties": {
+					"inner": {"type": "string"},
+					"name": {"type": "string"},
+					"age": {"type": "integer"},
+					"tags": {"type": "array", "items": {"type": "string"}},
+					"nickname": {"type": ["null", "string"]},
+					"meta": {"type": "object", "additionalProperties": {"type": "string"}}
+				}
+			}`,
+		},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := GenerateFromValue(v.given, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotAsMap := make(map[string]interface{})
+			wantAsMap := make(map[string]interface{})
+			if err := json.Unmarshal(got, &gotAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(v.want), &wantAsMap); err != nil {
+				t.Fatal(err)
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b interface{}) bool {
+				as, aok := a.(string)
+				bs, bok := b.(string)
+				return aok && bok && as < bs
+			})
+			if delta := cmp.Diff(gotAsMap, wantAsMap, sortStrings); delta != "" {
+				t.Logf("got %s", got)
+				t.Error(delta)
+			}
+		})
+	}
+}
+
+func TestGenerateFromTypeRecursiveType(t *testing.T) {
+	type Node struct {
+		Value    int     `json:"value"`
+		Children []*Node `json:"children"`
+	}
+
+	got, err := GenerateFromValue(Node{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+		"$schema": "http://json-schema.org/draft-07/schema",
+		"$ref": "#/$defs/Node",
+		"$defs": {
+			"Node": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["value", "children"],
+				"properties": {
+					"value": {"type": "integer"},
+					"children": {
+						"type": "array",
+						"items": {"$ref": "#/$defs/Node"}
+					}
+				}
+			}
+		}
+	}`
+
+	gotAsMap := make(map[string]interface{})
+	wantAsMap := make(map[string]interface{})
+	if err := json.Unmarshal(got, &gotAsMap); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantAsMap); err != nil {
+		t.Fatal(err)
+	}
+	sortStrings := cmpopts.SortSlices(func(a, b interface{}) bool {
+		as, aok := a.(string)
+		bs, bok := b.(string)
+		return aok && bok && as < bs
+	})
+	if delta := cmp.Diff(gotAsMap, wantAsMap, sortStrings); delta != "" {
+		t.Logf("got %s", got)
+		t.Error(delta)
+	}
+}
+
+func TestGenerateFromTypeRejectsNonStringMapKeys(t *testing.T) {
+	_, err := GenerateFromValue(map[int]string{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported map key type") {
+		t.Errorf("expected unsupported map key type error but got %v", err)
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	config := &SchemaConfig{ExtractDefinitions: true, RequireAllProperties: true}
+	view, err := GenerateSchema([]byte(`{"a": {"x": 1}, "b": {"x": 2}}`), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if view.Ref != "" {
+		t.Fatalf("expected no top-level $ref, got %q", view.Ref)
+	}
+	if view.Root == nil {
+		t.Fatal("expected a root property view")
+	}
+	if want := []string{"a", "b"}; !cmp.Equal(view.Root.Required, want) {
+		t.Errorf("Required = %v, want %v", view.Root.Required, want)
+	}
+
+	a, ok := view.Root.Properties["a"]
+	if !ok {
+		t.Fatal(`expected property "a"`)
+	}
+	if a.Ref == "" {
+		t.Fatal(`expected property "a" to be a $ref into Defs`)
+	}
+
+	def, ok := view.Defs[strings.TrimPrefix(a.Ref, "#/$defs/")]
+	if !ok {
+		t.Fatalf("expected a Defs entry for ref %q", a.Ref)
+	}
+	if want := []string{"integer"}; !cmp.Equal(def.Properties["x"].Type, want) {
+		t.Errorf("x.Type = %v, want %v", def.Properties["x"].Type, want)
+	}
+}
+
+func TestDraft202012ArrayModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		sample  string
+		config  *SchemaConfig
+		want    string
+		valid   []string
+		invalid []string
+	}{
+		{
+			name:   "ArrayModeList merges element shapes into a single items schema",
+			sample: `{"vals": [1, 2, 3]}`,
+			config: &SchemaConfig{Draft: Draft202012, RequireAllProperties: true},
+			want: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["vals"],
+				"properties": {
+					"vals": {"type": "array", "items": {"type": "integer"}}
+				}
+			}`,
+			valid:   []string{`{"vals": [4, 5]}`},
+			invalid: []string{`{"vals": ["x"]}`},
+		},
+		{
+			name:   "ArrayModeTuple renders prefixItems for an array of distinct shapes",
+			sample: `{"vals": [1, "a"]}`,
+			config: &SchemaConfig{Draft: Draft202012, ArrayMode: ArrayModeTuple, RequireAllProperties: true},
+			want: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["vals"],
+				"properties": {
+					"vals": {
+						"type": "array",
+						"prefixItems": [{"type": "integer"}, {"type": "string"}],
+						"items": false
+					}
+				}
+			}`,
+			valid:   []string{`{"vals": [2, "b"]}`},
+			invalid: []string{`{"vals": [2, "b", true]}`, `{"vals": ["b", 2]}`},
+		},
+		{
+			name:   "ArrayModeTuple falls back to a merged items schema when element shapes repeat",
+			sample: `{"vals": [1, 2, 3]}`,
+			config: &SchemaConfig{Draft: Draft202012, ArrayMode: ArrayModeTuple, RequireAllProperties: true},
+			want: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["vals"],
+				"properties": {
+					"vals": {"type": "array", "items": {"type": "integer"}}
+				}
+			}`,
+			valid:   []string{`{"vals": [9]}`},
+			invalid: []string{`{"vals": ["x"]}`},
+		},
+		{
+			name:   "ArrayModeTuple has no effect on Draft7 output",
+			sample: `{"vals": [1, "a"]}`,
+			config: &SchemaConfig{ArrayMode: ArrayModeTuple, RequireAllProperties: true},
+			want: `{
+				"$schema": "http://json-schema.org/draft-07/schema",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["vals"],
+				"properties": {
+					"vals": {
+						"type": "array",
+						"items": {"anyOf": [{"type": ["integer", "string"]}]}
+					}
+				}
+			}`,
+			valid:   []string{`{"vals": [1, 2, "a", "b"]}`},
+			invalid: []string{`{"vals": [true]}`},
+		},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := GenerateFromJSON([]byte(v.sample), v.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotAsMap := make(map[string]interface{})
+			wantAsMap := make(map[string]interface{})
+			if err := json.Unmarshal(got, &gotAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(v.want), &wantAsMap); err != nil {
+				t.Fatal(err)
+			}
+			if delta := cmp.Diff(gotAsMap, wantAsMap); delta != "" {
+				t.Logf("got %s", got)
+				t.Error(delta)
+			}
+
+			compiler := jsonschema.NewCompiler()
+			if err := compiler.AddResource("schema.json", bytes.NewReader(got)); err != nil {
+				t.Fatal(err)
+			}
+			sch, err := compiler.Compile("schema.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, doc := range v.valid {
+				var data interface{}
+				if err := json.Unmarshal([]byte(doc), &data); err != nil {
+					t.Fatal(err)
+				}
+				if err := sch.Validate(data); err != nil {
+					t.Errorf("expected %s to be valid against the generated schema: %v", doc, err)
+				}
+			}
+			for _, doc := range v.invalid {
+				var data interface{}
+				if err := json.Unmarshal([]byte(doc), &data); err != nil {
+					t.Fatal(err)
+				}
+				if err := sch.Validate(data); err == nil {
+					t.Errorf("expected %s to be invalid against the generated schema", doc)
+				}
+			}
+		})
+	}
+}
+
 func TestRejectSpecialYAML(t *testing.T) {
 	given := `42: "not supported"`
 	_, err := GenerateFromYAML([]byte(given), nil)